@@ -0,0 +1,241 @@
+package stringintern
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/segmentio/fasthash/fnv1a"
+)
+
+// shardsPerProc is the number of shards to create per GOMAXPROCS, rounded up
+// to the next power of two. More shards reduce contention between goroutines
+// at the cost of some extra memory overhead per shard.
+const shardsPerProc = 4
+
+// concurrentShard is an independent open-addressed table protected by its own
+// RWMutex, identical in structure to Set except it also keeps the global ID
+// assigned to each of its entries so a repeat Intern/Index can return it
+// without consulting the directory.
+type concurrentShard struct {
+	mu     sync.RWMutex
+	table  []int32
+	values []string
+	ids    []int64
+	mask   uint32
+}
+
+// dirEntry is one directory slot: the shard and local index a global ID was
+// assigned to. local is -1 for a slot reserved (to keep entries dense) but
+// not yet published by the goroutine that owns that ID.
+type dirEntry struct {
+	shard uint32
+	local int32
+}
+
+// dirBucket is one shard of the global directory, guarded by its own mutex
+// so IDs landing in different buckets don't contend.
+type dirBucket struct {
+	mu      sync.RWMutex
+	entries []dirEntry
+}
+
+// ConcurrentSet behaves like Set but is safe to call Intern/Index/StrValue on
+// from many goroutines at once. Strings are sharded across several
+// independently-locked tables, chosen from the high bits of the string's
+// hash, so that goroutines hashing to different shards do not contend. IDs
+// are handed out from a single global counter so they stay dense regardless
+// of how unevenly the shards fill; a sharded directory maps the low bits of
+// an ID back to the (shard, local index) it was actually stored at, so
+// StrValue resolves an ID with two loads: one into the directory, one into
+// the owning shard's values.
+type ConcurrentSet struct {
+	shards    []*concurrentShard
+	dirs      []*dirBucket
+	shardBits uint
+	shardMask uint32
+	nextID    atomic.Int64
+}
+
+// NewConcurrent returns an empty ConcurrentSet sized for the current
+// GOMAXPROCS.
+func NewConcurrent() *ConcurrentSet {
+	return NewConcurrentWithShards(runtime.GOMAXPROCS(0) * shardsPerProc)
+}
+
+// NewConcurrentWithShards returns an empty ConcurrentSet with numShards
+// shards, rounded up to the next power of two (minimum 1). The directory
+// has the same number of buckets as there are shards.
+func NewConcurrentWithShards(numShards int) *ConcurrentSet {
+	if numShards < 1 {
+		numShards = 1
+	}
+	shardBits := uint(bits.Len(uint(numShards - 1)))
+	n := 1 << shardBits
+
+	shards := make([]*concurrentShard, n)
+	dirs := make([]*dirBucket, n)
+	for i := range shards {
+		shards[i] = &concurrentShard{
+			table: make([]int32, minSize),
+			mask:  minSize - 1,
+		}
+		dirs[i] = &dirBucket{}
+	}
+	return &ConcurrentSet{
+		shards:    shards,
+		dirs:      dirs,
+		shardBits: shardBits,
+		shardMask: uint32(n - 1),
+	}
+}
+
+// shardFor returns the shard, its index, and the hash to use for v: the
+// shard is picked from the high bits of the hash so the low bits remain well
+// distributed for probing within the shard's table.
+func (s *ConcurrentSet) shardFor(v string) (*concurrentShard, uint32, uint32) {
+	h := fnv1a.HashString32(v)
+	shardIndex := (h >> (32 - s.shardBits)) & s.shardMask
+	return s.shards[shardIndex], shardIndex, h
+}
+
+// findSlot is Set.findSlot operating on one shard with a precomputed hash,
+// without tombstone handling: shards never delete entries.
+func (sh *concurrentShard) findSlot(v string, h uint32) (int, bool) {
+	slot := h & sh.mask
+	for {
+		indexPlusOne := sh.table[slot]
+		if indexPlusOne == 0 {
+			return int(slot), false
+		}
+		vIndex := indexPlusOne - 1
+		if sh.values[vIndex] == v {
+			return int(slot), true
+		}
+		slot = (slot + 1) & sh.mask
+	}
+}
+
+// publish records that id was assigned to shard/localIndex, growing the
+// owning directory bucket as needed. The bucket for id is always id's low
+// shardBits bits, and id's position within that bucket is always id's
+// remaining high bits, so this never races with concurrent publishes of
+// other IDs landing in the same bucket.
+func (s *ConcurrentSet) publish(id int64, shard uint32, localIndex int) {
+	bucket := s.dirs[uint32(id)&s.shardMask]
+	pos := int(id >> s.shardBits)
+
+	bucket.mu.Lock()
+	for len(bucket.entries) <= pos {
+		bucket.entries = append(bucket.entries, dirEntry{local: -1})
+	}
+	bucket.entries[pos] = dirEntry{shard: shard, local: int32(localIndex)}
+	bucket.mu.Unlock()
+}
+
+// Index returns the integer ID for v in the ConcurrentSet, or (0, false) if
+// it is not present.
+func (s *ConcurrentSet) Index(v string) (int, bool) {
+	sh, _, h := s.shardFor(v)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	slot, found := sh.findSlot(v, h)
+	if !found {
+		return 0, false
+	}
+	return int(sh.ids[sh.table[slot]-1]), true
+}
+
+// Intern returns the ID for v, adding it if it does not exist. It is safe to
+// call concurrently from multiple goroutines.
+func (s *ConcurrentSet) Intern(v string) int {
+	sh, shardIndex, h := s.shardFor(v)
+
+	sh.mu.RLock()
+	if slot, found := sh.findSlot(v, h); found {
+		id := sh.ids[sh.table[slot]-1]
+		sh.mu.RUnlock()
+		return int(id)
+	}
+	sh.mu.RUnlock()
+
+	sh.mu.Lock()
+	// re-check under the write lock: another goroutine may have added v
+	// between the RUnlock above and this Lock.
+	slot, found := sh.findSlot(v, h)
+	if found {
+		id := sh.ids[sh.table[slot]-1]
+		sh.mu.Unlock()
+		return int(id)
+	}
+
+	localIndex := len(sh.values)
+	maxSize := len(sh.table) * loadNumerator / loadDenominator
+	if localIndex >= maxSize {
+		sh.resize()
+		slot, found = sh.findSlot(v, h)
+		if found {
+			panic("BUG: must not be found after resize")
+		}
+	}
+	id := s.nextID.Add(1) - 1
+	sh.values = append(sh.values, v)
+	sh.ids = append(sh.ids, id)
+	sh.table[slot] = int32(localIndex + 1)
+	// publish while still holding sh.mu: any other goroutine that can see
+	// this table entry has necessarily waited for this lock, so by the time
+	// it reads sh.ids[...] the directory is guaranteed to already know id.
+	s.publish(id, shardIndex, localIndex)
+	sh.mu.Unlock()
+
+	return int(id)
+}
+
+func (sh *concurrentShard) resize() {
+	nextSize := len(sh.table) * 2
+	sh.table = make([]int32, nextSize)
+	sh.mask = uint32(nextSize - 1)
+
+	for i, v := range sh.values {
+		slot, found := sh.findSlot(v, fnv1a.HashString32(v))
+		if found {
+			panic("BUG: must not be found during resize")
+		}
+		sh.table[slot] = int32(i + 1)
+	}
+}
+
+// StrValue returns the string corresponding to id, or "", false if it does
+// not exist. It decodes id with two loads: one into the directory bucket id
+// hashes to, giving the (shard, local index) it was published at, and one
+// into that shard's values.
+func (s *ConcurrentSet) StrValue(id int) (string, bool) {
+	if id < 0 {
+		return "", false
+	}
+
+	bucket := s.dirs[uint32(id)&s.shardMask]
+	pos := id >> s.shardBits
+
+	bucket.mu.RLock()
+	if pos >= len(bucket.entries) {
+		bucket.mu.RUnlock()
+		return "", false
+	}
+	entry := bucket.entries[pos]
+	bucket.mu.RUnlock()
+
+	if entry.local < 0 {
+		return "", false
+	}
+
+	sh := s.shards[entry.shard]
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if int(entry.local) >= len(sh.values) {
+		return "", false
+	}
+	return sh.values[entry.local], true
+}
@@ -0,0 +1,138 @@
+package stringintern
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSetTrivial(t *testing.T) {
+	testStrings := []string{"foo", "bar", ""}
+
+	s := NewConcurrent()
+	for _, str := range testStrings {
+		_, ok := s.Index(str)
+		if ok {
+			t.Error(str, "should not be present yet")
+		}
+
+		index := s.Intern(str)
+		gotIndex, ok := s.Index(str)
+		if !(gotIndex == index && ok) {
+			t.Errorf("Index(%#v)=%d, %t; expected %d, %t", str, gotIndex, ok, index, true)
+		}
+		v, ok := s.StrValue(index)
+		if !(v == str && ok) {
+			t.Errorf("StrValue(%d)=%#v, %t; expected %#v, %t", index, v, ok, str, true)
+		}
+	}
+}
+
+// TestConcurrentSetDenseIDs guards against IDs spreading out across shards:
+// interning n distinct strings, however they land across shards, must
+// produce exactly the IDs 0..n-1, since callers use these IDs to index a
+// parallel array sized to the item count (see BenchmarkMapStringToString).
+func TestConcurrentSetDenseIDs(t *testing.T) {
+	const n = 20
+
+	s := NewConcurrentWithShards(8)
+	seen := make([]bool, n)
+	for i := 0; i < n; i++ {
+		index := s.Intern(strForInt(i))
+		if index < 0 || index >= n {
+			t.Fatalf("Intern(%#v)=%d; expected an ID in [0, %d)", strForInt(i), index, n)
+		}
+		if seen[index] {
+			t.Fatalf("Intern(%#v)=%d; ID already used by another string", strForInt(i), index)
+		}
+		seen[index] = true
+	}
+}
+
+func TestConcurrentSetConcurrentInterning(t *testing.T) {
+	const goroutines = 32
+	const perGoroutine = 1000
+
+	s := NewConcurrentWithShards(8)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				v := strForInt(g*perGoroutine + i)
+				index := s.Intern(v)
+				got, ok := s.StrValue(index)
+				if !(got == v && ok) {
+					t.Errorf("StrValue(%d)=%#v, %t; expected %#v, %t", index, got, ok, v, true)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for i := 0; i < goroutines*perGoroutine; i++ {
+		v := strForInt(i)
+		index, ok := s.Index(v)
+		if !ok {
+			t.Fatal("missing", v)
+			continue
+		}
+		got, ok := s.StrValue(index)
+		if !(got == v && ok) {
+			t.Errorf("StrValue(%d)=%#v, %t; expected %#v, %t", index, got, ok, v, true)
+		}
+	}
+}
+
+// TestConcurrentSetInternSameStringConcurrently guards against a race where
+// the loser of the race to insert a brand-new string could read the
+// winner's ID out of the shard table before the winner had published that
+// ID to the directory, making StrValue on a freshly-returned ID
+// intermittently report not-found.
+func TestConcurrentSetInternSameStringConcurrently(t *testing.T) {
+	const goroutines = 64
+	const v = "same-string"
+
+	s := NewConcurrentWithShards(1)
+	var wg sync.WaitGroup
+	ids := make([]int, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			id := s.Intern(v)
+			got, ok := s.StrValue(id)
+			if !(got == v && ok) {
+				t.Errorf("StrValue(%d) immediately after Intern(%#v) returned %#v, %t; expected %#v, %t", id, v, got, ok, v, true)
+			}
+			ids[g] = id
+		}(g)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Errorf("Intern(%#v) returned inconsistent IDs: %d and %d", v, ids[0], id)
+		}
+	}
+}
+
+func BenchmarkConcurrentIntern(b *testing.B) {
+	numItems := []int{100, 10000, 10000000}
+	for _, items := range numItems {
+		b.Run(fmt.Sprintf("concurrent-%d-items", items), func(b *testing.B) {
+			s := NewConcurrent()
+			fill(s, items)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					s.Intern(strForInt(i % items))
+					i++
+				}
+			})
+		})
+	}
+}
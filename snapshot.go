@@ -0,0 +1,190 @@
+package stringintern
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// snapshotMagic identifies the snapshot format written by WriteTo, so LoadSet
+// can reject unrelated data early instead of failing confusingly partway
+// through reading strings.
+const snapshotMagic = 0x53745331 // "StS1"
+const snapshotVersion = 2
+const snapshotHeaderLen = 4 + 4 + 4 + 4 + 4 // magic, version, count, loadNumerator, loadDenominator
+
+// maxPreallocEntries caps how many entries LoadSet will preallocate based on
+// the header's count field alone, before that count has been validated by
+// the CRC32 trailer. A corrupted count must not be able to trigger an
+// unbounded allocation; append grows s.values/s.deleted past this cap as
+// entries are actually read.
+const maxPreallocEntries = 1 << 16
+
+// deletedFlag/liveFlag precede each entry so LoadSet can tell a hole left by
+// Delete apart from a live, possibly empty, interned string.
+const liveFlag = 0
+const deletedFlag = 1
+
+// ErrCorruptSnapshot is returned by LoadSet when the data fails its CRC32
+// check or does not look like a stringintern snapshot.
+var ErrCorruptSnapshot = errors.New("stringintern: corrupt snapshot")
+
+// WriteTo writes a snapshot of s to w: a small header (magic, version,
+// count, load factor) followed by a live/deleted flag and length-prefixed
+// string for each entry in insertion order, and a CRC32 trailer covering
+// everything written before it. LoadSet reads this format back.
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	crc := crc32.NewIEEE()
+	out := io.MultiWriter(w, crc)
+
+	var total int64
+	header := make([]byte, snapshotHeaderLen)
+	binary.BigEndian.PutUint32(header[0:4], snapshotMagic)
+	binary.BigEndian.PutUint32(header[4:8], snapshotVersion)
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(s.values)))
+	binary.BigEndian.PutUint32(header[12:16], loadNumerator)
+	binary.BigEndian.PutUint32(header[16:20], loadDenominator)
+	n, err := out.Write(header)
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	lenBuf := make([]byte, 4)
+	for i, v := range s.values {
+		flag := byte(liveFlag)
+		if s.deleted[i] {
+			flag = deletedFlag
+		}
+		n, err = out.Write([]byte{flag})
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+
+		binary.BigEndian.PutUint32(lenBuf, uint32(len(v)))
+		n, err = out.Write(lenBuf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		n, err = io.WriteString(out, v)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	trailer := make([]byte, 4)
+	binary.BigEndian.PutUint32(trailer, crc.Sum32())
+	n, err = w.Write(trailer)
+	total += int64(n)
+	return total, err
+}
+
+// WriteToCompressed is equivalent to WriteTo, but wraps w in a snappy writer
+// first. Insertion-order string tables compress extremely well, and this
+// materially reduces the on-disk footprint of large sets.
+func (s *Set) WriteToCompressed(w io.Writer) (int64, error) {
+	sw := snappy.NewBufferedWriter(w)
+	n, err := s.WriteTo(sw)
+	if closeErr := sw.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// LoadSet reads a snapshot written by WriteTo (or WriteToCompressed, if r is
+// first wrapped in a snappy reader) and rebuilds a Set with exactly the same
+// IDs as the original: each entry becomes the value at that same index in
+// the new Set's values, whether live or a hole left by Delete. The table is
+// then rebuilt from scratch, sized the same way incremental resizing would
+// have sized it for this many entries, and live strings are placed into it
+// with findSlot.
+func LoadSet(r io.Reader) (*Set, error) {
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(r, crc)
+
+	header := make([]byte, snapshotHeaderLen)
+	if _, err := io.ReadFull(tr, header); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(header[0:4]) != snapshotMagic {
+		return nil, ErrCorruptSnapshot
+	}
+	if binary.BigEndian.Uint32(header[4:8]) != snapshotVersion {
+		return nil, ErrCorruptSnapshot
+	}
+	count := binary.BigEndian.Uint32(header[8:12])
+
+	prealloc := count
+	if prealloc > maxPreallocEntries {
+		prealloc = maxPreallocEntries
+	}
+
+	s := New()
+	s.values = make([]string, 0, prealloc)
+	s.deleted = make([]bool, 0, prealloc)
+
+	flagBuf := make([]byte, 1)
+	lenBuf := make([]byte, 4)
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(tr, flagBuf); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(tr, lenBuf); err != nil {
+			return nil, err
+		}
+		strLen := binary.BigEndian.Uint32(lenBuf)
+		// Read via io.ReadAll(io.LimitReader(...)) rather than
+		// make([]byte, strLen) + ReadFull: strLen is as unverified as count
+		// above, and ReadAll only grows its buffer as bytes actually arrive
+		// instead of committing to strLen up front.
+		strBuf, err := io.ReadAll(io.LimitReader(tr, int64(strLen)))
+		if err != nil {
+			return nil, err
+		}
+		if uint32(len(strBuf)) != strLen {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		deleted := flagBuf[0] == deletedFlag
+		s.deleted = append(s.deleted, deleted)
+		if deleted {
+			s.values = append(s.values, "")
+			s.numDeleted++
+		} else {
+			s.values = append(s.values, string(strBuf))
+		}
+	}
+
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(r, trailer); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(trailer) != crc.Sum32() {
+		return nil, ErrCorruptSnapshot
+	}
+
+	size := minSize
+	for len(s.values) > size*loadNumerator/loadDenominator {
+		size *= 2
+	}
+	s.table = make([]int32, size)
+	s.mask = uint32(size - 1)
+	for i, v := range s.values {
+		if s.deleted[i] {
+			continue
+		}
+		slot, found := s.findSlot(v)
+		if found {
+			panic("BUG: must not be found while rebuilding table")
+		}
+		s.table[slot] = int32(i + 1)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,169 @@
+package stringintern
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	const seeds = 10
+	const itemsPerSeed = 1000
+
+	for seed := 0; seed < seeds; seed++ {
+		rng := rand.New(rand.NewSource(int64(seed)))
+		s := New()
+		for i := 0; i < itemsPerSeed; i++ {
+			s.Intern(strForInt(rng.Intn(itemsPerSeed / 2)))
+		}
+
+		var buf bytes.Buffer
+		n, err := s.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(seed, err)
+		}
+		if n != int64(buf.Len()) {
+			t.Errorf("seed %d: WriteTo returned %d; buffer has %d bytes", seed, n, buf.Len())
+		}
+
+		loaded, err := LoadSet(&buf)
+		if err != nil {
+			t.Fatal(seed, err)
+		}
+
+		if !reflect.DeepEqual(s.values, loaded.values) {
+			t.Errorf("seed %d: values mismatch after round trip", seed)
+		}
+		if !reflect.DeepEqual(s.table, loaded.table) {
+			t.Errorf("seed %d: table mismatch after round trip", seed)
+		}
+		if s.mask != loaded.mask {
+			t.Errorf("seed %d: mask mismatch: %d != %d", seed, s.mask, loaded.mask)
+		}
+	}
+}
+
+func TestSnapshotCorruptionDetected(t *testing.T) {
+	s := New()
+	s.Intern("foo")
+	s.Intern("bar")
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	if _, err := LoadSet(bytes.NewReader(corrupted)); err != ErrCorruptSnapshot {
+		t.Errorf("LoadSet of corrupted data = %v; expected %v", err, ErrCorruptSnapshot)
+	}
+}
+
+// TestSnapshotHugeCountDetectedAsCorrupt guards against LoadSet trusting the
+// header's count field for preallocation before it has been CRC-verified: a
+// corrupted count claiming billions of entries must still fail with an
+// error (running out of real entry data surfaces as the underlying read
+// error, not necessarily ErrCorruptSnapshot) rather than attempting a huge
+// allocation up front.
+func TestSnapshotHugeCountDetectedAsCorrupt(t *testing.T) {
+	s := New()
+	s.Intern("foo")
+	s.Intern("bar")
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := buf.Bytes()
+	binary.BigEndian.PutUint32(corrupted[8:12], 1<<30)
+
+	if _, err := LoadSet(bytes.NewReader(corrupted)); err == nil {
+		t.Error("LoadSet with a corrupted, enormous count = nil error; expected an error")
+	}
+}
+
+// TestSnapshotHugeEntryLengthDetectedAsCorrupt is the same guard as
+// TestSnapshotHugeCountDetectedAsCorrupt, but for a single entry's
+// length prefix rather than the header's count: it is exactly as
+// unverified as count at the point it's read, so it must not be able to
+// trigger a huge allocation before the corruption is caught.
+func TestSnapshotHugeEntryLengthDetectedAsCorrupt(t *testing.T) {
+	s := New()
+	s.Intern("foo")
+	s.Intern("bar")
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := buf.Bytes()
+	// first entry's length prefix starts right after the header and that
+	// entry's flag byte
+	lenOffset := snapshotHeaderLen + 1
+	binary.BigEndian.PutUint32(corrupted[lenOffset:lenOffset+4], 1<<30)
+
+	if _, err := LoadSet(bytes.NewReader(corrupted)); err == nil {
+		t.Error("LoadSet with a corrupted, enormous entry length = nil error; expected an error")
+	}
+}
+
+func TestSnapshotRoundTripWithDeletions(t *testing.T) {
+	s := New()
+	for i := 0; i < 100; i++ {
+		s.Intern(strForInt(i))
+	}
+	for i := 0; i < 100; i += 2 {
+		if !s.Delete(strForInt(i)) {
+			t.Fatal("Delete of existing string failed", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSet(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != s.Len() {
+		t.Errorf("Len() = %d; expected %d", loaded.Len(), s.Len())
+	}
+	for i := 0; i < 100; i++ {
+		str := strForInt(i)
+		wantIndex, wantOK := s.Index(str)
+		gotIndex, gotOK := loaded.Index(str)
+		if wantIndex != gotIndex || wantOK != gotOK {
+			t.Errorf("Index(%#v) = %d, %t; expected %d, %t", str, gotIndex, gotOK, wantIndex, wantOK)
+		}
+	}
+}
+
+func TestSnapshotCompressed(t *testing.T) {
+	s := New()
+	for i := 0; i < 1000; i++ {
+		s.Intern(strForInt(i))
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.WriteToCompressed(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSet(snappy.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.values, loaded.values) {
+		t.Error("values mismatch after compressed round trip")
+	}
+}
@@ -12,15 +12,42 @@ const loadNumerator = 7
 const loadDenominator = 8
 const minSize = 16
 
+// tombstoneSlot marks a table slot whose entry was removed by Delete:
+// unlike 0 (never used), findSlot must keep probing past it rather than
+// treating it as the end of the probe chain.
+const tombstoneSlot int32 = -1
+
+// HashFunc computes a hash for v. Only the low 32 bits are used to index
+// into a Set's table, but implementations such as SwissSet's fingerprint
+// also rely on the high bits carrying real entropy.
+type HashFunc func(v string) uint64
+
+func fnv1aHash(v string) uint64 {
+	return fnv1a.HashString64(v)
+}
+
 type Set struct {
-	// table maps hash to index plus one so 0 == not present in table
-	table  []int32
-	values []string
-	mask   uint32
+	// table maps hash to index plus one so 0 == not present in table, and
+	// tombstoneSlot marks a slot whose entry was removed by Delete
+	table   []int32
+	values  []string
+	deleted []bool
+	mask    uint32
+	hash    HashFunc
+	// numDeleted is the number of true entries in deleted. It can exceed the
+	// number of tombstones left in table, since Intern reuses tombstone
+	// slots and resize does not copy tombstones into the new table.
+	numDeleted int
 }
 
 func New() *Set {
-	return &Set{make([]int32, minSize), nil, minSize - 1}
+	return NewWithHash(fnv1aHash)
+}
+
+// NewWithHash returns an empty Set that uses hash instead of the default
+// FNV-1a to place strings in the table.
+func NewWithHash(hash HashFunc) *Set {
+	return &Set{table: make([]int32, minSize), mask: minSize - 1, hash: hash}
 }
 
 // Index returns the integer index for v in the Set, or (0, false) if it is not present.
@@ -34,26 +61,49 @@ func (s *Set) Index(v string) (int, bool) {
 	return int(indexPlusOne - 1), true
 }
 
-// findSlot returns table index, found.
+// findSlot returns table index, found. It keeps probing past tombstoneSlot
+// entries left by Delete, remembering the first one so Intern can reuse it
+// instead of growing the probe chain further.
 func (s *Set) findSlot(v string) (int, bool) {
-	slot := fnv1a.HashString32(v) & s.mask
+	slot := uint32(s.hash(v)) & s.mask
+	firstTombstone := -1
 	for {
 		indexPlusOne := s.table[slot]
-		if indexPlusOne == 0 {
-			// unused slot: v belongs here
+		switch indexPlusOne {
+		case 0:
+			// unused slot: v belongs here, unless an earlier tombstone is free
+			if firstTombstone >= 0 {
+				return firstTombstone, false
+			}
 			return int(slot), false
-		}
-		vIndex := indexPlusOne - 1
-		if s.values[vIndex] == v {
-			// found the key at slot
-			return int(slot), true
+		case tombstoneSlot:
+			if firstTombstone < 0 {
+				firstTombstone = int(slot)
+			}
+		default:
+			vIndex := indexPlusOne - 1
+			if s.values[vIndex] == v {
+				// found the key at slot
+				return int(slot), true
+			}
 		}
 
 		slot = (slot + 1) & s.mask
 	}
 }
 
-// Intern returns the index for v, adding it if it does not exist.
+// Intern returns the index for v, adding it if it does not exist. Intern
+// never compacts the Set on its own: every ID it has ever returned stays
+// valid until the caller explicitly calls Compact, which can renumber
+// surviving entries.
+//
+// Intern does not compact however high the tombstone ratio gets:
+// compacting here would silently renumber IDs a caller may have already
+// cached (e.g. in an external map[string]int32, as
+// BenchmarkMapStringToString does), breaking the stable-ID contract the
+// rest of this package relies on. Compact plus
+// NumDeleted gives callers the same information with an explicit choice
+// of when to pay the renumbering cost.
 func (s *Set) Intern(v string) int {
 	slot, found := s.findSlot(v)
 	if found {
@@ -75,6 +125,7 @@ func (s *Set) Intern(v string) int {
 		}
 	}
 	s.values = append(s.values, v)
+	s.deleted = append(s.deleted, false)
 	s.table[slot] = int32(index + 1)
 	return index
 }
@@ -86,6 +137,9 @@ func (s *Set) resize() {
 	s.mask = uint32(nextSize - 1)
 
 	for i, v := range s.values {
+		if s.deleted[i] {
+			continue
+		}
 		slot, found := s.findSlot(v)
 		if found {
 			panic("BUG: must not be found during resize")
@@ -96,8 +150,82 @@ func (s *Set) resize() {
 
 // Get returns the string corresponding to index, or "", false if it does not exist.
 func (s *Set) StrValue(i int) (string, bool) {
-	if i < 0 || i >= len(s.values) {
+	if i < 0 || i >= len(s.values) || s.deleted[i] {
 		return "", false
 	}
 	return s.values[i], true
 }
+
+// Len returns the number of strings currently in the Set, which (unlike
+// cap(s.values)) does not count entries removed by Delete.
+func (s *Set) Len() int {
+	return len(s.values) - s.numDeleted
+}
+
+// NumDeleted returns the number of entries removed by Delete since the last
+// Compact (the holes left in values, not the tombstones still in table). Since Intern and Delete never compact on their own, a caller that
+// deletes strings from a long-lived Set should watch this (relative to Len,
+// or to table occupancy) and call Compact once it judges probe chains have
+// gotten too long.
+func (s *Set) NumDeleted() int {
+	return s.numDeleted
+}
+
+// Delete removes v from the Set, returning whether it was present. The
+// slot in table is left as a tombstone so findSlot keeps probing past it,
+// and the entry in values is cleared so the string can be garbage
+// collected; StrValue on its former index then returns "", false. Delete
+// never compacts the Set on its own (every other ID stays valid); call
+// Compact once enough tombstones have built up to keep probe chains short.
+func (s *Set) Delete(v string) bool {
+	slot, found := s.findSlot(v)
+	if !found {
+		return false
+	}
+
+	index := s.table[slot] - 1
+	s.table[slot] = tombstoneSlot
+	s.values[index] = ""
+	s.deleted[index] = true
+	s.numDeleted++
+	return true
+}
+
+// Compact reclaims the space used by deleted entries: it drops the holes
+// left in values, reassigns dense IDs to the remaining strings, and rebuilds
+// table from scratch. It returns an old ID -> new ID remap (-1 for IDs that
+// were deleted) so callers holding their own map[string]int32 of IDs can
+// rewrite them.
+func (s *Set) Compact() []int {
+	remap := make([]int, len(s.values))
+	newValues := make([]string, 0, len(s.values)-s.numDeleted)
+	newDeleted := make([]bool, 0, cap(newValues))
+	for i, v := range s.values {
+		if s.deleted[i] {
+			remap[i] = -1
+			continue
+		}
+		remap[i] = len(newValues)
+		newValues = append(newValues, v)
+		newDeleted = append(newDeleted, false)
+	}
+	s.values = newValues
+	s.deleted = newDeleted
+	s.numDeleted = 0
+
+	size := minSize
+	for len(s.values) >= size*loadNumerator/loadDenominator {
+		size *= 2
+	}
+	s.table = make([]int32, size)
+	s.mask = uint32(size - 1)
+	for i, v := range s.values {
+		slot, found := s.findSlot(v)
+		if found {
+			panic("BUG: must not be found during compact")
+		}
+		s.table[slot] = int32(i + 1)
+	}
+
+	return remap
+}
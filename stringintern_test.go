@@ -77,6 +77,128 @@ func TestTrivial(t *testing.T) {
 	}
 }
 
+func TestDelete(t *testing.T) {
+	s := New()
+	fooIndex := s.Intern("foo")
+	barIndex := s.Intern("bar")
+
+	if s.Delete("missing") {
+		t.Error("Delete of string that was never added returned true")
+	}
+	if s.Len() != 2 {
+		t.Errorf("Len()=%d; expected 2", s.Len())
+	}
+	if s.NumDeleted() != 0 {
+		t.Errorf("NumDeleted()=%d; expected 0", s.NumDeleted())
+	}
+
+	if !s.Delete("foo") {
+		t.Error("Delete(\"foo\") returned false")
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len()=%d; expected 1 after Delete", s.Len())
+	}
+	if s.NumDeleted() != 1 {
+		t.Errorf("NumDeleted()=%d; expected 1 after Delete", s.NumDeleted())
+	}
+	if v, ok := s.StrValue(fooIndex); ok {
+		t.Errorf("StrValue(%d)=%#v, %t; expected \"\", false after Delete", fooIndex, v, ok)
+	}
+	if _, ok := s.Index("foo"); ok {
+		t.Error("Index(\"foo\") found a deleted string")
+	}
+	if s.Delete("foo") {
+		t.Error("second Delete(\"foo\") returned true")
+	}
+
+	// bar is unaffected, and a new string can still be interned after a Delete
+	if v, ok := s.StrValue(barIndex); !(v == "bar" && ok) {
+		t.Errorf("StrValue(%d)=%#v, %t; expected \"bar\", true", barIndex, v, ok)
+	}
+	bazIndex := s.Intern("baz")
+	if v, ok := s.StrValue(bazIndex); !(v == "baz" && ok) {
+		t.Errorf("StrValue(%d)=%#v, %t; expected \"baz\", true", bazIndex, v, ok)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := New()
+	const n = 100
+	for i := 0; i < n; i++ {
+		s.Intern(strForInt(i))
+	}
+	for i := 0; i < n; i += 2 {
+		s.Delete(strForInt(i))
+	}
+
+	remap := s.Compact()
+	if len(remap) != n {
+		t.Fatalf("len(remap)=%d; expected %d", len(remap), n)
+	}
+	if s.Len() != n/2 {
+		t.Errorf("Len()=%d; expected %d after Compact", s.Len(), n/2)
+	}
+	if s.NumDeleted() != 0 {
+		t.Errorf("NumDeleted()=%d; expected 0 after Compact", s.NumDeleted())
+	}
+
+	for i := 0; i < n; i++ {
+		str := strForInt(i)
+		if i%2 == 0 {
+			if remap[i] != -1 {
+				t.Errorf("remap[%d]=%d; expected -1 for a deleted entry", i, remap[i])
+			}
+			if _, ok := s.Index(str); ok {
+				t.Errorf("Index(%#v) found a deleted string after Compact", str)
+			}
+			continue
+		}
+
+		newIndex, ok := s.Index(str)
+		if !ok {
+			t.Fatalf("Index(%#v) not found after Compact", str)
+		}
+		if newIndex != remap[i] {
+			t.Errorf("Index(%#v)=%d; expected remap[%d]=%d", str, newIndex, i, remap[i])
+		}
+		v, ok := s.StrValue(newIndex)
+		if !(v == str && ok) {
+			t.Errorf("StrValue(%d)=%#v, %t; expected %#v, true", newIndex, v, ok, str)
+		}
+	}
+}
+
+// TestInternNeverCompacts guards against Intern silently invalidating IDs a
+// caller has already cached: deleting nearly everything in the Set, then
+// calling Intern many more times, must never renumber the one survivor.
+// Only an explicit call to Compact is allowed to do that.
+func TestInternNeverCompacts(t *testing.T) {
+	s := New()
+	const n = 1000
+	for i := 0; i < n; i++ {
+		s.Intern(strForInt(i))
+	}
+	survivorIndex, ok := s.Index(strForInt(n - 1))
+	if !ok {
+		t.Fatal("survivor not found before deletions")
+	}
+	for i := 0; i < n-1; i++ {
+		s.Delete(strForInt(i))
+	}
+
+	// a high tombstone ratio must not make Intern compact on its own.
+	for i := n; i < n+2*n; i++ {
+		s.Intern(strForInt(i))
+	}
+
+	if v, ok := s.StrValue(survivorIndex); !(v == strForInt(n-1) && ok) {
+		t.Errorf("StrValue(%d)=%#v, %t; expected %#v, true: Intern must never invalidate a cached ID", survivorIndex, v, ok, strForInt(n-1))
+	}
+	if gotIndex, ok := s.Index(strForInt(n - 1)); !(ok && gotIndex == survivorIndex) {
+		t.Errorf("Index(%#v)=%d, %t; expected %d, true", strForInt(n-1), gotIndex, ok, survivorIndex)
+	}
+}
+
 func strForInt(i int) string {
 	return fmt.Sprintf("string%08d", i)
 }
@@ -86,7 +208,7 @@ func TestImplementations(t *testing.T) {
 	const operationsPerSeed = 100000
 
 	for seed := 0; seed < seeds; seed++ {
-		implementations := []stringSet{newMapSet(), New()}
+		implementations := []stringSet{newMapSet(), New(), NewSwiss()}
 		numExisting := 0
 
 		rng := rand.New(rand.NewSource(int64(seed)))
@@ -219,6 +341,23 @@ func BenchmarkFill(b *testing.B) {
 			}
 			b.StopTimer()
 
+			mem.Stop()
+			b.ReportMetric(float64(mem.inUse())/float64(items), "B/item")
+			if s.Intern("QQQQQ") < 0 {
+				panic("ensure map is not GCed")
+			}
+		})
+		b.Run(fmt.Sprintf("swiss-%d-items", items), func(b *testing.B) {
+			mem.Start()
+			var s *SwissSet
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s = NewSwiss()
+				fill(s, items)
+			}
+			b.StopTimer()
+
 			mem.Stop()
 			b.ReportMetric(float64(mem.inUse())/float64(items), "B/item")
 			if s.Intern("QQQQQ") < 0 {
@@ -250,6 +389,15 @@ func BenchmarkIntern(b *testing.B) {
 			s := New()
 			fill(s, items)
 
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				escape += s.Intern(strForInt(i % items))
+			}
+		})
+		b.Run(fmt.Sprintf("swiss-%d-items", items), func(b *testing.B) {
+			s := NewSwiss()
+			fill(s, items)
+
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
 				escape += s.Intern(strForInt(i % items))
@@ -0,0 +1,172 @@
+package stringintern
+
+import "math/bits"
+
+// swissLoadNumerator/swissLoadDenominator is 14/16, the same ratio as the
+// plain Set's 7/8: the fingerprint bytes mean most negative lookups never
+// touch the values slice, so SwissSet stays fast even when the table is
+// nearly full.
+const swissLoadNumerator = 14
+const swissLoadDenominator = 16
+
+// swissGroupSize is the number of slots checked together as one 8-byte word,
+// matching the width of the SWAR byte-equality trick used below. Real
+// SIMD SwissTable implementations use 16-wide groups with actual vector
+// instructions; 8-wide is the natural width for a portable word-at-a-time
+// Go implementation.
+const swissGroupSize = 8
+
+// swissEmpty is the fingerprint sentinel for a slot that has never been
+// written. Occupied slots always have fingerprintOccupied set, so they can
+// never collide with it.
+const swissEmpty = 0
+const fingerprintOccupied = 0x80
+const fingerprintMask = 0x7f
+
+// SwissSet behaves like Set, but keeps a parallel byte per table slot
+// holding a 7-bit hash fingerprint. Probing compares fingerprints 8 at a
+// time using SWAR (SIMD-within-a-register) bit tricks before ever touching
+// the values slice, which avoids a cache miss on the common case of probing
+// past a slot that belongs to a different string.
+type SwissSet struct {
+	table        []int32
+	fingerprints []uint8
+	values       []string
+	mask         uint32
+	hash         HashFunc
+}
+
+func NewSwiss() *SwissSet {
+	return NewSwissWithHash(fnv1aHash)
+}
+
+// NewSwissWithHash returns an empty SwissSet that uses hash instead of the
+// default FNV-1a to place strings in the table.
+func NewSwissWithHash(hash HashFunc) *SwissSet {
+	return &SwissSet{
+		table:        make([]int32, minSize),
+		fingerprints: make([]uint8, minSize),
+		mask:         minSize - 1,
+		hash:         hash,
+	}
+}
+
+// fingerprintOf derives a 7-bit fingerprint from the top bits of h, so it
+// stays independent of the low bits findSlot uses to pick the starting slot.
+func fingerprintOf(h uint64) uint8 {
+	return fingerprintOccupied | (uint8(h>>57) & fingerprintMask)
+}
+
+// matchByteMask returns a uint64 with the top bit of each byte lane set
+// where that lane in word equals b. This is the classic SWAR "find a zero
+// byte" trick generalized to an arbitrary target byte by XORing it away
+// first. The lowest set lane is always exact, but the subtraction's borrow
+// can also set lanes above a match whose value is b^0x01; findSlot only
+// uses the lowest empty lane, and confirms every fingerprint match by
+// comparing the strings.
+func matchByteMask(word uint64, b uint8) uint64 {
+	const lo = 0x0101010101010101
+	const hi = 0x8080808080808080
+	x := word ^ (lo * uint64(b))
+	return (x - lo) &^ x & hi
+}
+
+// loadGroup reads swissGroupSize consecutive fingerprints starting at slot
+// (wrapping around the table), packing them little-endian into a uint64 so
+// byte i of the word is the fingerprint at slot+i.
+func (s *SwissSet) loadGroup(slot uint32) uint64 {
+	var word uint64
+	for i := uint32(0); i < swissGroupSize; i++ {
+		word |= uint64(s.fingerprints[(slot+i)&s.mask]) << (8 * i)
+	}
+	return word
+}
+
+// findSlot returns the table index for v (the slot to insert at if not
+// found), and whether v is already present.
+func (s *SwissSet) findSlot(v string) (int, bool) {
+	h := s.hash(v)
+	fp := fingerprintOf(h)
+	slot := uint32(h) & s.mask
+
+	for {
+		word := s.loadGroup(slot)
+		emptyMask := matchByteMask(word, swissEmpty)
+		matchMask := matchByteMask(word, fp)
+
+		limit := swissGroupSize
+		if emptyMask != 0 {
+			limit = bits.TrailingZeros64(emptyMask) / 8
+		}
+
+		for i := 0; i < limit; i++ {
+			if matchMask&(uint64(0x80)<<(8*i)) != 0 {
+				candidate := (slot + uint32(i)) & s.mask
+				if s.values[s.table[candidate]-1] == v {
+					return int(candidate), true
+				}
+			}
+		}
+		if emptyMask != 0 {
+			return int((slot + uint32(limit)) & s.mask), false
+		}
+		slot = (slot + swissGroupSize) & s.mask
+	}
+}
+
+// Index returns the integer index for v in the SwissSet, or (0, false) if it
+// is not present.
+func (s *SwissSet) Index(v string) (int, bool) {
+	slot, found := s.findSlot(v)
+	if !found {
+		return 0, false
+	}
+	return int(s.table[slot] - 1), true
+}
+
+// Intern returns the index for v, adding it if it does not exist.
+func (s *SwissSet) Intern(v string) int {
+	slot, found := s.findSlot(v)
+	if found {
+		return int(s.table[slot] - 1)
+	}
+
+	index := len(s.values)
+	maxSize := len(s.table) * swissLoadNumerator / swissLoadDenominator
+	if index >= maxSize {
+		s.resize()
+		slot, found = s.findSlot(v)
+		if found {
+			panic("BUG: must not be found after resize")
+		}
+	}
+	s.values = append(s.values, v)
+	s.table[slot] = int32(index + 1)
+	s.fingerprints[slot] = fingerprintOf(s.hash(v))
+	return index
+}
+
+func (s *SwissSet) resize() {
+	nextSize := len(s.table) * 2
+	s.table = make([]int32, nextSize)
+	s.fingerprints = make([]uint8, nextSize)
+	s.mask = uint32(nextSize - 1)
+
+	for i, v := range s.values {
+		slot, found := s.findSlot(v)
+		if found {
+			panic("BUG: must not be found during resize")
+		}
+		s.table[slot] = int32(i + 1)
+		s.fingerprints[slot] = fingerprintOf(s.hash(v))
+	}
+}
+
+// StrValue returns the string corresponding to index, or "", false if it
+// does not exist.
+func (s *SwissSet) StrValue(i int) (string, bool) {
+	if i < 0 || i >= len(s.values) {
+		return "", false
+	}
+	return s.values[i], true
+}
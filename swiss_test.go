@@ -0,0 +1,26 @@
+package stringintern
+
+import "testing"
+
+// TestSwissFingerprintsNotDegenerate guards against the fingerprint byte
+// collapsing to a single constant value (e.g. if the hash feeding it only
+// has entropy in bits fingerprintOf doesn't look at), which would defeat the
+// fingerprint fast path entirely and leave SwissSet scanning every occupied
+// slot in a group on every probe.
+func TestSwissFingerprintsNotDegenerate(t *testing.T) {
+	s := NewSwiss()
+	for i := 0; i < 1000; i++ {
+		s.Intern(strForInt(i))
+	}
+
+	seen := make(map[uint8]bool)
+	for _, fp := range s.fingerprints {
+		if fp == swissEmpty {
+			continue
+		}
+		seen[fp] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("fingerprints are degenerate: saw only %d distinct value(s) across %d entries", len(seen), len(s.values))
+	}
+}